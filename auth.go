@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/shazow/ssh-chat/chat"
+	"golang.org/x/crypto/ssh"
+)
+
+// Auth is consulted by the sshd handshake callback for every incoming
+// connection: it enforces an optional public key whitelist and checks the
+// connecting name, IP, key fingerprint, and client version string against
+// Bans.
+type Auth struct {
+	mu      sync.Mutex
+	allowed map[string]struct{}
+
+	// Bans is the underlying TTL cache of banned names/IPs/fingerprints/
+	// client versions, also driving the `/ban` and `/banned` commands.
+	Bans *chat.BanList
+}
+
+// NewAuth creates an Auth with an empty whitelist and ban list.
+func NewAuth() *Auth {
+	return &Auth{
+		Bans: chat.NewBanList(),
+	}
+}
+
+// Whitelist adds key to the set of explicitly allowed public keys. Once the
+// whitelist is non-empty, only connections presenting a whitelisted key are
+// permitted; see Permitted.
+func (a *Auth) Whitelist(key ssh.PublicKey) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.allowed == nil {
+		a.allowed = map[string]struct{}{}
+	}
+	a.allowed[ssh.FingerprintSHA256(key)] = struct{}{}
+}
+
+// Permitted reports whether key may connect. With no whitelist configured,
+// every key is permitted.
+func (a *Auth) Permitted(key ssh.PublicKey) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.allowed) == 0 {
+		return true
+	}
+	_, ok := a.allowed[ssh.FingerprintSHA256(key)]
+	return ok
+}
+
+// Check consults the ban list for meta's name, remote IP and client version,
+// and for key's fingerprint if present. It's meant to be called from an sshd handshake
+// callback; a false result means the connection should be rejected and
+// reason sent to the client before disconnecting. See AuthCallback, which
+// builds exactly that callback -- there's no sshd server in this tree yet
+// to actually pass it to.
+func (a *Auth) Check(meta ssh.ConnMetadata, key ssh.PublicKey) (ok bool, reason string) {
+	checks := []struct {
+		t     chat.BanType
+		value string
+	}{
+		{chat.BanName, meta.User()},
+		{chat.BanIP, hostOnly(meta.RemoteAddr())},
+		{chat.BanClientVersion, string(meta.ClientVersion())},
+	}
+	if key != nil {
+		checks = append(checks, struct {
+			t     chat.BanType
+			value string
+		}{chat.BanFingerprint, ssh.FingerprintSHA256(key)})
+	}
+
+	for _, c := range checks {
+		if reason, banned := a.Bans.Check(c.t, c.value); banned {
+			if reason == "" {
+				reason = "banned"
+			}
+			return false, reason
+		}
+	}
+	return true, ""
+}
+
+// Ban bans value under the given type for duration d (0 for indefinite).
+func (a *Auth) Ban(t chat.BanType, value string, d time.Duration, reason string) error {
+	return a.Bans.Add(t, value, d, reason)
+}
+
+// SetBanFile persists bans to path, loading any already stored there.
+func (a *Auth) SetBanFile(path string) error {
+	return a.Bans.SetFile(path)
+}
+
+// hostOnly strips the port from a dialed address, falling back to the full
+// string if it isn't a host:port pair.
+func hostOnly(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
+// AuthCallback builds an ssh.ServerConfig.PublicKeyCallback backed by a,
+// enforcing both the whitelist (Permitted) and the ban list (Check) for
+// every key a connecting client offers:
+//
+//	config := &ssh.ServerConfig{PublicKeyCallback: AuthCallback(auth)}
+//
+// Returning an error here makes the ssh package reject the offered key and
+// try the next one (or fail the handshake if there isn't one), which is how
+// x/crypto/ssh expects a PublicKeyCallback to refuse a connection.
+func AuthCallback(a *Auth) func(ssh.ConnMetadata, ssh.PublicKey) (*ssh.Permissions, error) {
+	return func(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+		if !a.Permitted(key) {
+			return nil, errors.New("public key rejected")
+		}
+		if ok, reason := a.Check(meta, key); !ok {
+			return nil, fmt.Errorf("connection refused: %s", reason)
+		}
+		return nil, nil
+	}
+}
+
+// RegisterCommands adds `/ban` and `/banned` to cs, backed by a's ban list.
+// `/ban` takes the same arguments as chat.ParseBanCommand; `/banned` takes
+// none and lists every active ban.
+func (a *Auth) RegisterCommands(cs *chat.CommandSet) {
+	cs.Add("ban", func(args []string) (string, error) {
+		t, value, d, err := chat.ParseBanCommand(args)
+		if err != nil {
+			return "", err
+		}
+		if err := a.Ban(t, value, d, ""); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Banned %s: %s", t, value), nil
+	})
+	cs.Add("banned", func(args []string) (string, error) {
+		return chat.FormatBanned(a.Bans.List()), nil
+	})
+}
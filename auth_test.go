@@ -0,0 +1,174 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"net"
+	"testing"
+
+	"github.com/shazow/ssh-chat/chat"
+	"golang.org/x/crypto/ssh"
+)
+
+// fakeAddr is a minimal net.Addr for tests that just need a host:port string.
+type fakeAddr string
+
+func (a fakeAddr) Network() string { return "tcp" }
+func (a fakeAddr) String() string  { return string(a) }
+
+// fakeConnMeta is a minimal ssh.ConnMetadata for driving Auth.Check without
+// a real SSH handshake.
+type fakeConnMeta struct {
+	user          string
+	remoteAddr    string
+	clientVersion string
+}
+
+func (f fakeConnMeta) User() string          { return f.user }
+func (f fakeConnMeta) SessionID() []byte     { return nil }
+func (f fakeConnMeta) ClientVersion() []byte { return []byte(f.clientVersion) }
+func (f fakeConnMeta) ServerVersion() []byte { return nil }
+func (f fakeConnMeta) RemoteAddr() net.Addr  { return fakeAddr(f.remoteAddr) }
+func (f fakeConnMeta) LocalAddr() net.Addr   { return fakeAddr("") }
+
+func testSigner(t *testing.T) ssh.Signer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+func TestAuthCheckOrdering(t *testing.T) {
+	a := NewAuth()
+	meta := fakeConnMeta{user: "mallory", remoteAddr: "1.2.3.4:2222", clientVersion: "SSH-2.0-evil"}
+	key := testSigner(t).PublicKey()
+
+	// No bans: permitted.
+	if ok, reason := a.Check(meta, key); !ok {
+		t.Errorf("expected no ban to pass, got reason %q", reason)
+	}
+
+	// A ban on a type that doesn't match this connection shouldn't trigger.
+	if err := a.Ban(chat.BanName, "someone-else", 0, ""); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := a.Check(meta, key); !ok {
+		t.Error("expected unrelated name ban not to match")
+	}
+
+	// Name ban should match before IP/fingerprint/client-version are even relevant.
+	if err := a.Ban(chat.BanName, "mallory", 0, "spammer"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, reason := a.Check(meta, key); ok || reason != "spammer" {
+		t.Errorf("got (%v, %q); expected name ban to reject with reason", ok, reason)
+	}
+
+	// IP ban on a fresh Auth (no name ban) should also reject.
+	a2 := NewAuth()
+	if err := a2.Ban(chat.BanIP, "1.2.3.4", 0, ""); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := a2.Check(meta, key); ok {
+		t.Error("expected IP ban to reject")
+	}
+
+	// Client-version ban.
+	a3 := NewAuth()
+	if err := a3.Ban(chat.BanClientVersion, "SSH-2.0-evil", 0, ""); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := a3.Check(meta, key); ok {
+		t.Error("expected client-version ban to reject")
+	}
+
+	// Fingerprint ban.
+	a4 := NewAuth()
+	if err := a4.Ban(chat.BanFingerprint, ssh.FingerprintSHA256(key), 0, ""); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := a4.Check(meta, key); ok {
+		t.Error("expected fingerprint ban to reject")
+	}
+}
+
+func TestAuthCheckNilKey(t *testing.T) {
+	a := NewAuth()
+	meta := fakeConnMeta{user: "alice", remoteAddr: "5.6.7.8:22"}
+
+	// With a nil key, the fingerprint check is skipped entirely rather than
+	// banning everyone with no key (e.g. keyboard-interactive connections).
+	if ok, _ := a.Check(meta, nil); !ok {
+		t.Error("expected nil key with no bans to pass")
+	}
+
+	key := testSigner(t).PublicKey()
+	if err := a.Ban(chat.BanFingerprint, ssh.FingerprintSHA256(key), 0, ""); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := a.Check(meta, nil); !ok {
+		t.Error("expected a fingerprint ban to be irrelevant when key is nil")
+	}
+}
+
+func TestHostOnly(t *testing.T) {
+	if got := hostOnly(fakeAddr("1.2.3.4:2222")); got != "1.2.3.4" {
+		t.Errorf("got %q; expected 1.2.3.4", got)
+	}
+	if got := hostOnly(fakeAddr("not-a-host-port")); got != "not-a-host-port" {
+		t.Errorf("got %q; expected the original string unchanged", got)
+	}
+}
+
+func TestAuthCallback(t *testing.T) {
+	a := NewAuth()
+	cb := AuthCallback(a)
+	meta := fakeConnMeta{user: "mallory", remoteAddr: "1.2.3.4:2222", clientVersion: "SSH-2.0-test"}
+	key := testSigner(t).PublicKey()
+
+	if _, err := cb(meta, key); err != nil {
+		t.Errorf("expected unbanned connection to be accepted, got %s", err)
+	}
+
+	if err := a.Ban(chat.BanName, "mallory", 0, "spammer"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cb(meta, key); err == nil {
+		t.Error("expected banned connection to be rejected")
+	}
+
+	a2 := NewAuth()
+	a2.Whitelist(testSigner(t).PublicKey())
+	if _, err := AuthCallback(a2)(meta, key); err == nil {
+		t.Error("expected a non-whitelisted key to be rejected")
+	}
+}
+
+func TestAuthRegisterCommands(t *testing.T) {
+	a := NewAuth()
+	cs := chat.NewCommandSet()
+	a.RegisterCommands(cs)
+
+	if _, err := cs.Run("/ban name mallory 10m"); err != nil {
+		t.Fatal(err)
+	}
+
+	reply, err := cs.Run("/banned")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply == "No bans in effect." {
+		t.Error("expected /banned to reflect the new ban")
+	}
+
+	meta := fakeConnMeta{user: "mallory"}
+	if ok, _ := a.Check(meta, nil); ok {
+		t.Error("expected /ban to have actually banned mallory")
+	}
+}
@@ -0,0 +1,32 @@
+// Package backend provides pluggable broadcast/presence implementations for
+// chat.Room, so a room can either stay in-process (Memory) or fan out
+// through a shared store (Redis) when multiple ssh-chat nodes sit behind one
+// TCP load balancer. chat.Room depends on its own Backend interface rather
+// than importing MessageBackend directly (to avoid chat <-> chat/backend
+// becoming an import cycle), but Memory and Redis satisfy it structurally,
+// so either can be passed straight into chat.NewRoom.
+package backend
+
+import "github.com/shazow/ssh-chat/chat"
+
+// MessageBackend is the delivery and presence contract a Room depends on;
+// see chat.Backend, which this interface is kept in sync with.
+type MessageBackend interface {
+	// Publish broadcasts msg to every subscriber of the room, on every
+	// node.
+	Publish(msg chat.Message) error
+
+	// Subscribe returns a channel of messages published to the room.
+	// Redis drops a node's own Publish calls rather than delivering them
+	// back to that same node; Memory, being a single node, delivers every
+	// Publish to every subscriber.
+	Subscribe() <-chan chat.Message
+
+	// Join and Leave update room presence so /names reflects users
+	// connected to any node.
+	Join(name string) error
+	Leave(name string) error
+
+	// Names lists everyone present in the room, across all nodes.
+	Names() ([]string, error)
+}
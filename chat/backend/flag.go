@@ -0,0 +1,54 @@
+package backend
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseFlag interprets a Host `--backend` flag value, such as
+// "redis://localhost:6379" or the default "memory", returning the scheme
+// and the remaining address to dial.
+func ParseFlag(spec string) (scheme, addr string, err error) {
+	if spec == "" || spec == "memory" {
+		return "memory", "", nil
+	}
+
+	parts := strings.SplitN(spec, "://", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("backend: invalid flag %q, expected scheme://address", spec)
+	}
+	return parts[0], parts[1], nil
+}
+
+// Dial connects to a RedisClient at addr, as parsed out of a `--backend`
+// flag by ParseFlag. It's a function value rather than a hard dependency so
+// this package doesn't pin the tree to one Redis driver; the caller
+// supplies a Dial backed by whichever client library is actually vendored.
+type Dial func(addr string) (RedisClient, error)
+
+// New builds the MessageBackend named by a `--backend` flag value: "memory"
+// (or "") for Memory, or "redis://host:port" for Redis, dialed via dial.
+// room and nodeID are only used for the redis scheme, identifying the
+// shared room key and this process for echo suppression, respectively.
+func New(spec, room, nodeID string, dial Dial) (MessageBackend, error) {
+	scheme, addr, err := ParseFlag(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	switch scheme {
+	case "memory":
+		return NewMemory(), nil
+	case "redis":
+		if dial == nil {
+			return nil, fmt.Errorf("backend: %q requires a redis client dialer", spec)
+		}
+		client, err := dial(addr)
+		if err != nil {
+			return nil, fmt.Errorf("backend: dialing redis at %q: %s", addr, err)
+		}
+		return NewRedis(client, room, nodeID), nil
+	default:
+		return nil, fmt.Errorf("backend: unknown scheme %q", scheme)
+	}
+}
@@ -0,0 +1,80 @@
+package backend
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseFlag(t *testing.T) {
+	cases := []struct {
+		spec       string
+		scheme     string
+		addr       string
+		shouldFail bool
+	}{
+		{"", "memory", "", false},
+		{"memory", "memory", "", false},
+		{"redis://localhost:6379", "redis", "localhost:6379", false},
+		{"bogus", "", "", true},
+		{"redis://", "", "", true},
+	}
+
+	for _, c := range cases {
+		scheme, addr, err := ParseFlag(c.spec)
+		if c.shouldFail {
+			if err == nil {
+				t.Errorf("ParseFlag(%q): expected error", c.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseFlag(%q): unexpected error: %s", c.spec, err)
+			continue
+		}
+		if scheme != c.scheme || addr != c.addr {
+			t.Errorf("ParseFlag(%q) = (%q, %q); expected (%q, %q)", c.spec, scheme, addr, c.scheme, c.addr)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	b, err := New("memory", "lobby", "node-a", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := b.(*Memory); !ok {
+		t.Errorf("got %T; expected *Memory", b)
+	}
+
+	dialed := false
+	dial := func(addr string) (RedisClient, error) {
+		dialed = true
+		if addr != "localhost:6379" {
+			t.Errorf("got dial addr %q", addr)
+		}
+		return newFakeRedis(), nil
+	}
+	b, err = New("redis://localhost:6379", "lobby", "node-a", dial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := b.(*Redis); !ok {
+		t.Errorf("got %T; expected *Redis", b)
+	}
+	if !dialed {
+		t.Error("expected New to call dial for a redis:// spec")
+	}
+
+	if _, err := New("redis://localhost:6379", "lobby", "node-a", nil); err == nil {
+		t.Error("expected an error when no dialer is supplied for redis")
+	}
+
+	dialErr := func(addr string) (RedisClient, error) { return nil, errors.New("boom") }
+	if _, err := New("redis://localhost:6379", "lobby", "node-a", dialErr); err == nil {
+		t.Error("expected New to propagate a dial error")
+	}
+
+	if _, err := New("bogus", "lobby", "node-a", nil); err == nil {
+		t.Error("expected an error for an invalid flag")
+	}
+}
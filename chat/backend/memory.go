@@ -0,0 +1,71 @@
+package backend
+
+import (
+	"sync"
+
+	"github.com/shazow/ssh-chat/chat"
+)
+
+// Memory is the default MessageBackend: broadcast and presence are kept
+// in-process, for a single ssh-chat node.
+type Memory struct {
+	mu    sync.Mutex
+	subs  map[chan chat.Message]struct{}
+	names map[string]struct{}
+}
+
+var _ MessageBackend = (*Memory)(nil)
+
+// NewMemory creates an empty in-process backend.
+func NewMemory() *Memory {
+	return &Memory{
+		subs:  map[chan chat.Message]struct{}{},
+		names: map[string]struct{}{},
+	}
+}
+
+// Publish implements MessageBackend.
+func (m *Memory) Publish(msg chat.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for ch := range m.subs {
+		ch <- msg
+	}
+	return nil
+}
+
+// Subscribe implements MessageBackend.
+func (m *Memory) Subscribe() <-chan chat.Message {
+	ch := make(chan chat.Message, 16)
+	m.mu.Lock()
+	m.subs[ch] = struct{}{}
+	m.mu.Unlock()
+	return ch
+}
+
+// Join implements MessageBackend.
+func (m *Memory) Join(name string) error {
+	m.mu.Lock()
+	m.names[name] = struct{}{}
+	m.mu.Unlock()
+	return nil
+}
+
+// Leave implements MessageBackend.
+func (m *Memory) Leave(name string) error {
+	m.mu.Lock()
+	delete(m.names, name)
+	m.mu.Unlock()
+	return nil
+}
+
+// Names implements MessageBackend.
+func (m *Memory) Names() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.names))
+	for name := range m.names {
+		names = append(names, name)
+	}
+	return names, nil
+}
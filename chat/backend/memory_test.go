@@ -0,0 +1,54 @@
+package backend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shazow/ssh-chat/chat"
+)
+
+func TestMemoryPublishSubscribe(t *testing.T) {
+	m := NewMemory()
+	sub := m.Subscribe()
+
+	msg := chat.NewPublicMessage(chat.Id("alice"), "hi", time.Now())
+	if err := m.Publish(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case got := <-sub:
+		if got.Body() != "hi" {
+			t.Errorf("got body %q; expected %q", got.Body(), "hi")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for published message")
+	}
+}
+
+func TestMemoryPresence(t *testing.T) {
+	m := NewMemory()
+
+	if err := m.Join("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Join("bob"); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := m.Names()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 {
+		t.Errorf("got %d names; expected 2", len(names))
+	}
+
+	if err := m.Leave("alice"); err != nil {
+		t.Fatal(err)
+	}
+	names, _ = m.Names()
+	if len(names) != 1 || names[0] != "bob" {
+		t.Errorf("got %v; expected [bob]", names)
+	}
+}
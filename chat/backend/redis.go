@@ -0,0 +1,173 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shazow/ssh-chat/chat"
+)
+
+// RedisClient is the minimal surface the Redis backend needs from a pub/sub
+// client library (e.g. github.com/redis/go-redis). It's kept small and
+// interface-based so this package doesn't pin the tree to one driver.
+type RedisClient interface {
+	Publish(channel, payload string) error
+	Subscribe(channel string) (<-chan string, error)
+	SetWithTTL(key, value string, ttl time.Duration) error
+	Get(key string) (value string, ok bool, err error)
+	Del(key string) error
+	Keys(pattern string) ([]string, error)
+}
+
+// presenceTTL bounds how long a node's presence entry survives without a
+// heartbeat, so a crashed node's users eventually drop out of /names.
+const presenceTTL = 30 * time.Second
+
+// Redis is a MessageBackend that publishes through a shared Redis pub/sub
+// channel, so multiple ssh-chat processes behind one TCP load balancer can
+// serve a single logical room. Presence is tracked with one key per user,
+// refreshed by Heartbeat so stale nodes expire out.
+type Redis struct {
+	client RedisClient
+	room   string
+	nodeID string
+}
+
+var _ MessageBackend = (*Redis)(nil)
+
+// NewRedis creates a Redis backend for room, publishing under nodeID so the
+// backend can recognize and drop its own echoes.
+func NewRedis(client RedisClient, room, nodeID string) *Redis {
+	return &Redis{client: client, room: room, nodeID: nodeID}
+}
+
+// wireMessage is the JSON form of a chat.Message sent over Redis pub/sub.
+type wireMessage struct {
+	NodeID string           `json:"node_id"`
+	Type   chat.MessageType `json:"type"`
+	From   string           `json:"from"`
+	To     string           `json:"to,omitempty"`
+	Body   string           `json:"body"`
+	Sent   time.Time        `json:"sent"`
+}
+
+func encodeMessage(nodeID string, msg chat.Message) ([]byte, error) {
+	w := wireMessage{
+		NodeID: nodeID,
+		Type:   msg.Type(),
+		From:   string(msg.From()),
+		Body:   msg.Body(),
+		Sent:   msg.Sent(),
+	}
+	if pm, ok := msg.(chat.PrivateMessage); ok {
+		w.To = string(pm.To())
+	}
+	return json.Marshal(w)
+}
+
+func decodeMessage(data []byte) (msg chat.Message, nodeID string, err error) {
+	var w wireMessage
+	if err := json.Unmarshal(data, &w); err != nil {
+		return nil, "", err
+	}
+
+	switch w.Type {
+	case chat.MessagePublic:
+		msg = chat.NewPublicMessage(chat.Id(w.From), w.Body, w.Sent)
+	case chat.MessageEmote:
+		msg = chat.NewEmoteMessage(chat.Id(w.From), w.Body, w.Sent)
+	case chat.MessageAnnounce:
+		msg = chat.NewAnnounceMessage(w.Body, w.Sent)
+	case chat.MessagePrivate:
+		msg = chat.NewPrivateMessage(chat.Id(w.From), chat.Id(w.To), w.Body, w.Sent)
+	case chat.MessageSystem:
+		msg = chat.NewSystemMessage(w.Body, w.Sent)
+	default:
+		return nil, "", fmt.Errorf("backend: unknown message type %d", w.Type)
+	}
+	return msg, w.NodeID, nil
+}
+
+func (b *Redis) channel() string {
+	return "room:" + b.room
+}
+
+func (b *Redis) presenceKey(name string) string {
+	return fmt.Sprintf("presence:%s:%s", b.room, name)
+}
+
+// Publish implements MessageBackend.
+func (b *Redis) Publish(msg chat.Message) error {
+	payload, err := encodeMessage(b.nodeID, msg)
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(b.channel(), string(payload))
+}
+
+// Subscribe implements MessageBackend. It drops malformed payloads and this
+// node's own publishes rather than surfacing them as delivered messages.
+func (b *Redis) Subscribe() <-chan chat.Message {
+	out := make(chan chat.Message)
+
+	raw, err := b.client.Subscribe(b.channel())
+	if err != nil {
+		close(out)
+		return out
+	}
+
+	go func() {
+		defer close(out)
+		for payload := range raw {
+			msg, nodeID, err := decodeMessage([]byte(payload))
+			if err != nil || nodeID == b.nodeID {
+				continue
+			}
+			out <- msg
+		}
+	}()
+	return out
+}
+
+// Join implements MessageBackend, recording this node as name's owner until
+// the next Heartbeat or presenceTTL, whichever comes first.
+func (b *Redis) Join(name string) error {
+	return b.client.SetWithTTL(b.presenceKey(name), b.nodeID, presenceTTL)
+}
+
+// Leave implements MessageBackend, immediately deleting name's presence key
+// so it drops out of Names without waiting for presenceTTL to pass.
+func (b *Redis) Leave(name string) error {
+	return b.client.Del(b.presenceKey(name))
+}
+
+// Heartbeat refreshes name's presence TTL; callers should call this
+// periodically (well under presenceTTL) for as long as name is connected to
+// this node.
+func (b *Redis) Heartbeat(name string) error {
+	return b.Join(name)
+}
+
+// Names implements MessageBackend, listing every user with a live presence
+// key in this room, regardless of which node they're connected to.
+func (b *Redis) Names() ([]string, error) {
+	keys, err := b.client.Keys(fmt.Sprintf("presence:%s:*", b.room))
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := fmt.Sprintf("presence:%s:", b.room)
+	names := make([]string, 0, len(keys))
+	for _, key := range keys {
+		names = append(names, strings.TrimPrefix(key, prefix))
+	}
+	return names, nil
+}
+
+// Locate returns the node ID that currently owns name's connection, for
+// routing a PM to the right node.
+func (b *Redis) Locate(name string) (node string, ok bool, err error) {
+	return b.client.Get(b.presenceKey(name))
+}
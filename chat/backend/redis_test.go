@@ -0,0 +1,206 @@
+package backend
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"testing"
+
+	"github.com/shazow/ssh-chat/chat"
+)
+
+// fakeRedis is an in-memory stand-in for a real Redis client, just enough
+// to drive Redis's pub/sub and presence logic in tests.
+type fakeRedis struct {
+	mu   sync.Mutex
+	subs map[string][]chan string
+	kv   map[string]string
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{
+		subs: map[string][]chan string{},
+		kv:   map[string]string{},
+	}
+}
+
+func (f *fakeRedis) Publish(channel, payload string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, ch := range f.subs[channel] {
+		ch <- payload
+	}
+	return nil
+}
+
+func (f *fakeRedis) Subscribe(channel string) (<-chan string, error) {
+	ch := make(chan string, 16)
+	f.mu.Lock()
+	f.subs[channel] = append(f.subs[channel], ch)
+	f.mu.Unlock()
+	return ch, nil
+}
+
+func (f *fakeRedis) SetWithTTL(key, value string, ttl time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.kv[key] = value
+	return nil
+}
+
+func (f *fakeRedis) Get(key string) (string, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	v, ok := f.kv[key]
+	return v, ok, nil
+}
+
+func (f *fakeRedis) Del(key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.kv, key)
+	return nil
+}
+
+func (f *fakeRedis) Keys(pattern string) ([]string, error) {
+	prefix := strings.TrimSuffix(pattern, "*")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []string
+	for k := range f.kv {
+		if strings.HasPrefix(k, prefix) {
+			out = append(out, k)
+		}
+	}
+	return out, nil
+}
+
+func TestRedisPublishSubscribeAcrossNodes(t *testing.T) {
+	client := newFakeRedis()
+	nodeA := NewRedis(client, "lobby", "node-a")
+	nodeB := NewRedis(client, "lobby", "node-b")
+
+	subB := nodeB.Subscribe()
+
+	msg := chat.NewPublicMessage(chat.Id("alice"), "hello", time.Now())
+	if err := nodeA.Publish(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case got := <-subB:
+		if got.Body() != "hello" || got.From() != chat.Id("alice") {
+			t.Errorf("got %+v", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for cross-node message")
+	}
+}
+
+func TestRedisSuppressesOwnEcho(t *testing.T) {
+	client := newFakeRedis()
+	node := NewRedis(client, "lobby", "node-a")
+	sub := node.Subscribe()
+
+	if err := node.Publish(chat.NewPublicMessage(chat.Id("alice"), "hi", time.Now())); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case got := <-sub:
+		t.Fatalf("expected own publish to be suppressed, got %+v", got)
+	case <-time.After(50 * time.Millisecond):
+		// Expected: nothing delivered.
+	}
+}
+
+func TestRedisPresenceAndLocate(t *testing.T) {
+	client := newFakeRedis()
+	node := NewRedis(client, "lobby", "node-a")
+
+	if err := node.Join("alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := node.Names()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0] != "alice" {
+		t.Errorf("got %v; expected [alice]", names)
+	}
+
+	owner, ok, err := node.Locate("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || owner != "node-a" {
+		t.Errorf("got (%q, %v); expected (node-a, true)", owner, ok)
+	}
+
+	if _, ok, _ := node.Locate("bob"); ok {
+		t.Error("expected bob to have no presence entry")
+	}
+}
+
+func TestRedisLeaveRemovesPresenceImmediately(t *testing.T) {
+	client := newFakeRedis()
+	node := NewRedis(client, "lobby", "node-a")
+
+	if err := node.Join("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := node.Leave("alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := node.Names()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 0 {
+		t.Errorf("got %v; expected Leave to remove presence immediately, not wait for TTL", names)
+	}
+
+	if _, ok, _ := node.Locate("alice"); ok {
+		t.Error("expected alice to have no presence entry after Leave")
+	}
+}
+
+func TestEncodeDecodePrivateMessage(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	pm := chat.NewPrivateMessage(chat.Id("alice"), chat.Id("bob"), "psst", now)
+
+	data, err := encodeMessage("node-a", pm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, nodeID, err := decodeMessage(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nodeID != "node-a" {
+		t.Errorf("got node id %q", nodeID)
+	}
+
+	got, ok := msg.(chat.PrivateMessage)
+	if !ok {
+		t.Fatalf("got %T; expected chat.PrivateMessage", msg)
+	}
+	if got.To() != chat.Id("bob") || got.From() != chat.Id("alice") || got.Body() != "psst" {
+		t.Errorf("unexpected round-trip: %+v", got)
+	}
+	if !got.Sent().Equal(now) {
+		t.Errorf("got sent %v; expected %v", got.Sent(), now)
+	}
+}
+
+func TestDecodeMessageUnknownType(t *testing.T) {
+	_, _, err := decodeMessage([]byte(fmt.Sprintf(`{"node_id":"n","type":99,"body":"x"}`)))
+	if err == nil {
+		t.Error("expected error for unknown message type")
+	}
+}
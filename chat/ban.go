@@ -0,0 +1,227 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// BanType identifies which attribute of a connection a ban entry matches
+// against.
+type BanType int
+
+const (
+	BanName BanType = iota
+	BanIP
+	BanFingerprint
+	BanClientVersion
+)
+
+// String returns the command-line spelling of a BanType, as accepted by
+// ParseBanType.
+func (t BanType) String() string {
+	switch t {
+	case BanName:
+		return "name"
+	case BanIP:
+		return "ip"
+	case BanFingerprint:
+		return "fingerprint"
+	case BanClientVersion:
+		return "client-version"
+	}
+	return "unknown"
+}
+
+// ParseBanType parses the type argument of a `/ban` command.
+func ParseBanType(s string) (BanType, error) {
+	switch s {
+	case "name":
+		return BanName, nil
+	case "ip":
+		return BanIP, nil
+	case "fingerprint":
+		return BanFingerprint, nil
+	case "client-version":
+		return BanClientVersion, nil
+	}
+	return 0, fmt.Errorf("unknown ban type: %q", s)
+}
+
+// BanRecord is a read-only view of a single active ban, used for listing.
+type BanRecord struct {
+	Type    BanType
+	Value   string
+	Reason  string
+	Expires time.Time
+}
+
+// Expired reports whether the ban is no longer in effect at t.
+func (r BanRecord) Expired(t time.Time) bool {
+	return !r.Expires.IsZero() && t.After(r.Expires)
+}
+
+type banKey struct {
+	Type  BanType
+	Value string
+}
+
+// banFileEntry is the on-disk representation of a BanRecord.
+type banFileEntry struct {
+	Type    BanType   `json:"type"`
+	Value   string    `json:"value"`
+	Reason  string    `json:"reason,omitempty"`
+	Expires time.Time `json:"expires,omitempty"`
+}
+
+// BanList is a TTL cache of bans keyed by (BanType, value), consulted by the
+// sshd handshake callback and managed via the `/ban` and `/banned` admin
+// commands. Expired entries are pruned lazily on access.
+type BanList struct {
+	mu   sync.Mutex
+	bans map[banKey]BanRecord
+	path string
+}
+
+// NewBanList creates an empty BanList.
+func NewBanList() *BanList {
+	return &BanList{
+		bans: map[banKey]BanRecord{},
+	}
+}
+
+// SetFile sets the path bans are persisted to and loads any bans already
+// stored there. Pass an empty string to disable persistence.
+func (b *BanList) SetFile(path string) error {
+	b.mu.Lock()
+	b.path = path
+	b.mu.Unlock()
+	return b.load()
+}
+
+// Add bans value under the given type. A zero duration bans indefinitely;
+// otherwise the ban expires after d.
+func (b *BanList) Add(t BanType, value string, d time.Duration, reason string) error {
+	if value == "" {
+		return fmt.Errorf("ban value must not be empty")
+	}
+	rec := BanRecord{Type: t, Value: value, Reason: reason}
+	if d > 0 {
+		rec.Expires = time.Now().Add(d)
+	}
+
+	b.mu.Lock()
+	b.bans[banKey{t, value}] = rec
+	err := b.save()
+	b.mu.Unlock()
+	return err
+}
+
+// Remove clears any ban on (t, value).
+func (b *BanList) Remove(t BanType, value string) error {
+	b.mu.Lock()
+	delete(b.bans, banKey{t, value})
+	err := b.save()
+	b.mu.Unlock()
+	return err
+}
+
+// Check looks up (t, value) and returns the active ban reason, if any.
+// Expired entries are removed and treated as not banned.
+func (b *BanList) Check(t BanType, value string) (reason string, banned bool) {
+	if value == "" {
+		return "", false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := banKey{t, value}
+	rec, ok := b.bans[key]
+	if !ok {
+		return "", false
+	}
+	if rec.Expired(time.Now()) {
+		delete(b.bans, key)
+		return "", false
+	}
+	return rec.Reason, true
+}
+
+// List returns all non-expired bans, grouped by type, for the `/banned`
+// command. Expired entries are pruned as a side effect.
+func (b *BanList) List() map[BanType][]BanRecord {
+	now := time.Now()
+	out := map[BanType][]BanRecord{}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for key, rec := range b.bans {
+		if rec.Expired(now) {
+			delete(b.bans, key)
+			continue
+		}
+		out[key.Type] = append(out[key.Type], rec)
+	}
+	return out
+}
+
+// save writes the ban list to disk. Must be called with mu held.
+func (b *BanList) save() error {
+	if b.path == "" {
+		return nil
+	}
+
+	entries := make([]banFileEntry, 0, len(b.bans))
+	for key, rec := range b.bans {
+		entries = append(entries, banFileEntry{
+			Type:    key.Type,
+			Value:   key.Value,
+			Reason:  rec.Reason,
+			Expires: rec.Expires,
+		})
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(b.path, data, 0644)
+}
+
+// load reads the ban list from disk, if a path is set and it exists.
+func (b *BanList) load() error {
+	b.mu.Lock()
+	path := b.path
+	b.mu.Unlock()
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var entries []banFileEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, e := range entries {
+		b.bans[banKey{e.Type, e.Value}] = BanRecord{
+			Type:    e.Type,
+			Value:   e.Value,
+			Reason:  e.Reason,
+			Expires: e.Expires,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package chat
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBanListCheck(t *testing.T) {
+	b := NewBanList()
+
+	if _, banned := b.Check(BanName, "foo"); banned {
+		t.Error("expected no ban before Add")
+	}
+
+	if err := b.Add(BanName, "foo", 0, "spamming"); err != nil {
+		t.Fatal(err)
+	}
+
+	reason, banned := b.Check(BanName, "foo")
+	if !banned {
+		t.Error("expected foo to be banned")
+	}
+	if reason != "spamming" {
+		t.Errorf("got reason %q; expected %q", reason, "spamming")
+	}
+
+	if _, banned := b.Check(BanIP, "foo"); banned {
+		t.Error("ban on BanName should not apply to BanIP")
+	}
+}
+
+func TestBanListExpiry(t *testing.T) {
+	b := NewBanList()
+
+	if err := b.Add(BanIP, "1.2.3.4", time.Millisecond, "temp"); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+
+	if _, banned := b.Check(BanIP, "1.2.3.4"); banned {
+		t.Error("expected ban to have expired")
+	}
+}
+
+func TestBanListRemove(t *testing.T) {
+	b := NewBanList()
+	b.Add(BanFingerprint, "abc", 0, "")
+
+	if err := b.Remove(BanFingerprint, "abc"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, banned := b.Check(BanFingerprint, "abc"); banned {
+		t.Error("expected ban to be removed")
+	}
+}
+
+func TestBanListList(t *testing.T) {
+	b := NewBanList()
+	b.Add(BanName, "foo", 0, "")
+	b.Add(BanName, "bar", 0, "")
+	b.Add(BanIP, "1.2.3.4", 0, "")
+
+	grouped := b.List()
+	if len(grouped[BanName]) != 2 {
+		t.Errorf("got %d name bans; expected 2", len(grouped[BanName]))
+	}
+	if len(grouped[BanIP]) != 1 {
+		t.Errorf("got %d ip bans; expected 1", len(grouped[BanIP]))
+	}
+}
+
+func TestBanListPersist(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bans.json")
+
+	b := NewBanList()
+	if err := b.SetFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Add(BanClientVersion, "SSH-2.0-legacy", time.Hour, "broken client"); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := NewBanList()
+	if err := reloaded.SetFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	reason, banned := reloaded.Check(BanClientVersion, "SSH-2.0-legacy")
+	if !banned {
+		t.Fatal("expected ban to survive reload")
+	}
+	if reason != "broken client" {
+		t.Errorf("got reason %q; expected %q", reason, "broken client")
+	}
+}
+
+func TestParseBanType(t *testing.T) {
+	cases := map[string]BanType{
+		"name":           BanName,
+		"ip":             BanIP,
+		"fingerprint":    BanFingerprint,
+		"client-version": BanClientVersion,
+	}
+	for s, want := range cases {
+		got, err := ParseBanType(s)
+		if err != nil {
+			t.Errorf("ParseBanType(%q) returned error: %s", s, err)
+		}
+		if got != want {
+			t.Errorf("ParseBanType(%q) = %v; want %v", s, got, want)
+		}
+	}
+
+	if _, err := ParseBanType("bogus"); err == nil {
+		t.Error("expected error for unknown ban type")
+	}
+}
@@ -0,0 +1,123 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrNotACommand is returned by CommandSet.Run when line doesn't start with
+// "/", i.e. it's ordinary room content rather than a command.
+var ErrNotACommand = errors.New("chat: not a command")
+
+// Handler runs a command's body (the words after its name) and returns the
+// reply to send back to whoever issued it.
+type Handler func(args []string) (reply string, err error)
+
+// CommandSet is a name -> Handler dispatch table for slash commands like
+// `/ban` and `/banned`. It knows nothing about who issued a command or how
+// the reply gets delivered; callers (e.g. Room) own that.
+type CommandSet struct {
+	handlers map[string]Handler
+}
+
+// NewCommandSet creates an empty CommandSet.
+func NewCommandSet() *CommandSet {
+	return &CommandSet{handlers: map[string]Handler{}}
+}
+
+// Add registers handler under name (without the leading "/"), replacing any
+// existing handler of the same name.
+func (c *CommandSet) Add(name string, handler Handler) {
+	c.handlers[name] = handler
+}
+
+// Run parses line as "/name arg1 arg2 ..." and runs the matching handler.
+// It returns ErrNotACommand if line doesn't start with "/", and an
+// "unknown command" error if no handler is registered under name.
+func (c *CommandSet) Run(line string) (reply string, err error) {
+	if !strings.HasPrefix(line, "/") {
+		return "", ErrNotACommand
+	}
+	fields := strings.Fields(line[1:])
+	if len(fields) == 0 {
+		return "", fmt.Errorf("chat: empty command")
+	}
+
+	name, args := fields[0], fields[1:]
+	handler, ok := c.handlers[name]
+	if !ok {
+		return "", fmt.Errorf("chat: unknown command: /%s", name)
+	}
+	return handler(args)
+}
+
+// ParseBanCommand parses the arguments to `/ban <type> <value> [duration]`.
+// Duration is optional and parsed with time.ParseDuration; when omitted the
+// ban does not expire.
+func ParseBanCommand(args []string) (t BanType, value string, d time.Duration, err error) {
+	if len(args) < 2 {
+		err = fmt.Errorf("usage: /ban <type> <value> [duration]")
+		return
+	}
+
+	t, err = ParseBanType(args[0])
+	if err != nil {
+		return
+	}
+	value = args[1]
+
+	if len(args) > 2 {
+		d, err = time.ParseDuration(args[2])
+		if err != nil {
+			err = fmt.Errorf("invalid duration %q: %s", args[2], err)
+			return
+		}
+	}
+	return
+}
+
+// FormatBanned renders the grouped ban listing produced by BanList.List for
+// the `/banned` command.
+func FormatBanned(banned map[BanType][]BanRecord) string {
+	if len(banned) == 0 {
+		return "No bans in effect."
+	}
+
+	types := make([]BanType, 0, len(banned))
+	for t := range banned {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+	var lines []string
+	for _, t := range types {
+		records := banned[t]
+		sort.Slice(records, func(i, j int) bool { return records[i].Value < records[j].Value })
+
+		lines = append(lines, fmt.Sprintf("[%s]", t))
+		for _, rec := range records {
+			line := "  " + rec.Value
+			if rec.Reason != "" {
+				line += ": " + rec.Reason
+			}
+			if !rec.Expires.IsZero() {
+				line += fmt.Sprintf(" (expires %s)", rec.Expires.Format(time.RFC3339))
+			}
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// FormatWhois renders the identity line of `/whois` output: the verified
+// fingerprint and time, or "identity: not verified" when identity is nil
+// (the target has none on file).
+func FormatWhois(identity *Identity) string {
+	if identity == nil {
+		return "identity: not verified"
+	}
+	return fmt.Sprintf("identity: verified %s (%s)", identity.Fingerprint, identity.VerifiedAt.Format(time.RFC3339))
+}
@@ -0,0 +1,82 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseBanCommand(t *testing.T) {
+	typ, value, d, err := ParseBanCommand([]string{"name", "foo", "10m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ != BanName || value != "foo" || d != 10*time.Minute {
+		t.Errorf("got (%v, %q, %v)", typ, value, d)
+	}
+
+	typ, value, d, err = ParseBanCommand([]string{"ip", "1.2.3.4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ != BanIP || value != "1.2.3.4" || d != 0 {
+		t.Errorf("got (%v, %q, %v); expected no expiry", typ, value, d)
+	}
+
+	if _, _, _, err := ParseBanCommand([]string{"name"}); err == nil {
+		t.Error("expected error with missing value")
+	}
+
+	if _, _, _, err := ParseBanCommand([]string{"name", "foo", "soon"}); err == nil {
+		t.Error("expected error with invalid duration")
+	}
+}
+
+func TestFormatBanned(t *testing.T) {
+	if got := FormatBanned(map[BanType][]BanRecord{}); got != "No bans in effect." {
+		t.Errorf("got %q for empty ban list", got)
+	}
+
+	banned := map[BanType][]BanRecord{
+		BanName: {{Type: BanName, Value: "foo", Reason: "spam"}},
+	}
+	got := FormatBanned(banned)
+	if !strings.Contains(got, "[name]") || !strings.Contains(got, "foo: spam") {
+		t.Errorf("unexpected output: %q", got)
+	}
+}
+
+func TestFormatWhois(t *testing.T) {
+	if got := FormatWhois(nil); got != "identity: not verified" {
+		t.Errorf("got %q for nil identity", got)
+	}
+
+	identity := NewIdentity(Id("alice"), "SHA256:abc123", time.Now())
+	got := FormatWhois(identity)
+	if !strings.Contains(got, "SHA256:abc123") {
+		t.Errorf("unexpected output: %q", got)
+	}
+}
+
+func TestCommandSetRun(t *testing.T) {
+	cs := NewCommandSet()
+	cs.Add("echo", func(args []string) (string, error) {
+		return strings.Join(args, " "), nil
+	})
+
+	reply, err := cs.Run("/echo hello world")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply != "hello world" {
+		t.Errorf("got %q", reply)
+	}
+
+	if _, err := cs.Run("/nope"); err == nil {
+		t.Error("expected error for unregistered command")
+	}
+
+	if _, err := cs.Run("not a command"); err != ErrNotACommand {
+		t.Errorf("got %v; expected ErrNotACommand", err)
+	}
+}
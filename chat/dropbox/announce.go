@@ -0,0 +1,39 @@
+package dropbox
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shazow/ssh-chat/chat"
+)
+
+// Upload stores data as name on owner's behalf, same as Put, but also
+// builds the chat.AnnounceMessage a Room should broadcast so other members
+// learn the file is available via `/get <token>`.
+func (d *Dropbox) Upload(owner, name string, data []byte) (token string, announce chat.Message, err error) {
+	token, err = d.Put(owner, name, data)
+	if err != nil {
+		return "", nil, err
+	}
+	body := fmt.Sprintf("%s shared %s: /get %s", owner, name, token)
+	return token, chat.NewAnnounceMessage(body, time.Now()), nil
+}
+
+// RegisterCommands adds `/get <token>` to cs, returning the stored file's
+// name and contents as the command reply. A chat.CommandSet's Handler only
+// carries a string reply, which is enough for text snippets but can't
+// stream arbitrary binary data to a client; an actual file transfer still
+// needs a real scp/SFTP session to drive it, which is unrelated to the
+// storage this package provides.
+func (d *Dropbox) RegisterCommands(cs *chat.CommandSet) {
+	cs.Add("get", func(args []string) (string, error) {
+		if len(args) != 1 {
+			return "", fmt.Errorf("usage: /get <token>")
+		}
+		name, data, err := d.Get(args[0])
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s:\n%s", name, data), nil
+	})
+}
@@ -0,0 +1,62 @@
+package dropbox
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shazow/ssh-chat/chat"
+)
+
+func TestUpload(t *testing.T) {
+	d := New(1<<20, 1<<20, time.Minute)
+
+	token, msg, err := d.Upload("alice", "hello.txt", []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token == "" {
+		t.Fatal("expected a non-empty token")
+	}
+	if msg.Type() != chat.MessageAnnounce {
+		t.Errorf("got message type %v; expected MessageAnnounce", msg.Type())
+	}
+	if !strings.Contains(msg.Body(), "alice") || !strings.Contains(msg.Body(), "hello.txt") || !strings.Contains(msg.Body(), token) {
+		t.Errorf("got announce body %q", msg.Body())
+	}
+
+	name, data, err := d.Get(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "hello.txt" || string(data) != "hello" {
+		t.Errorf("got (%q, %q)", name, data)
+	}
+}
+
+func TestRegisterCommandsGet(t *testing.T) {
+	d := New(1<<20, 1<<20, time.Minute)
+	cs := chat.NewCommandSet()
+	d.RegisterCommands(cs)
+
+	token, _, err := d.Upload("alice", "hello.txt", []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reply, err := cs.Run("/get " + token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(reply, "hello.txt") || !strings.Contains(reply, "hello") {
+		t.Errorf("got %q", reply)
+	}
+
+	if _, err := cs.Run("/get nonexistent"); err != ErrNotFound {
+		t.Errorf("got %v; expected ErrNotFound", err)
+	}
+
+	if _, err := cs.Run("/get"); err == nil {
+		t.Error("expected /get with no token to fail")
+	}
+}
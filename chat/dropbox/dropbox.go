@@ -0,0 +1,194 @@
+// Package dropbox is a bounded in-memory store for files shared in a room
+// and retrieved with `/get <token>`. Upload (see announce.go) stores a file
+// and builds the chat.AnnounceMessage a Room should broadcast, and
+// RegisterCommands wires `/get` itself up to a chat.CommandSet; neither
+// depends on how the file bytes reached this package. Accepting the upload
+// side over `scp -t <name>` or SFTP is a separate, ssh-session-level
+// concern: it needs an actual exec/subsystem request handler to call
+// Upload, which this package intentionally has no opinion on.
+package dropbox
+
+import (
+	"container/list"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Default limits for a Dropbox created with NewDefault.
+const (
+	DefaultTotalQuota = 32 << 20 // 32 MB across all users.
+	DefaultUserQuota  = 4 << 20  // 4 MB per user.
+	DefaultTTL        = 10 * time.Minute
+)
+
+// ErrTooLarge is returned when a single file exceeds the total quota, so it
+// could never fit regardless of what else is evicted.
+var ErrTooLarge = errors.New("dropbox: file exceeds total quota")
+
+// ErrQuotaExceeded is returned when storing a file would exceed the
+// uploader's per-user quota, counted after expired files are dropped.
+var ErrQuotaExceeded = errors.New("dropbox: quota exceeded")
+
+// ErrNotFound is returned for an unknown or already-expired token.
+var ErrNotFound = errors.New("dropbox: unknown token")
+
+// entry is one stored file, also the list.List payload used for LRU order.
+type entry struct {
+	token   string
+	owner   string
+	name    string
+	data    []byte
+	expires time.Time
+}
+
+// Dropbox is a bounded, per-user-quota'd, TTL'd LRU cache of uploaded files,
+// keyed by a random download token.
+type Dropbox struct {
+	mu         sync.Mutex
+	totalQuota int64
+	userQuota  int64
+	ttl        time.Duration
+
+	used   int64
+	byUser map[string]int64
+	index  map[string]*list.Element
+	order  *list.List // front = most recently used
+}
+
+// New creates a Dropbox with the given total quota, per-user quota, and
+// token TTL.
+func New(totalQuota, userQuota int64, ttl time.Duration) *Dropbox {
+	return &Dropbox{
+		totalQuota: totalQuota,
+		userQuota:  userQuota,
+		ttl:        ttl,
+		byUser:     map[string]int64{},
+		index:      map[string]*list.Element{},
+		order:      list.New(),
+	}
+}
+
+// NewDefault creates a Dropbox using the package's default limits.
+func NewDefault() *Dropbox {
+	return New(DefaultTotalQuota, DefaultUserQuota, DefaultTTL)
+}
+
+// Put stores data as name on owner's behalf and returns a download token for
+// `/get`. Storing first drops expired files, then evicts least-recently-used
+// files, whoever owns them (owner's own included), until the total quota has
+// room.
+func (d *Dropbox) Put(owner, name string, data []byte) (token string, err error) {
+	size := int64(len(data))
+	if size > d.totalQuota {
+		return "", ErrTooLarge
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.evictExpiredLocked()
+
+	if d.byUser[owner]+size > d.userQuota {
+		return "", ErrQuotaExceeded
+	}
+	for d.used+size > d.totalQuota {
+		if !d.evictOldestLocked() {
+			return "", ErrQuotaExceeded
+		}
+	}
+
+	token, err = newToken()
+	if err != nil {
+		return "", err
+	}
+
+	e := &entry{token: token, owner: owner, name: name, data: data, expires: time.Now().Add(d.ttl)}
+	d.index[token] = d.order.PushFront(e)
+	d.used += size
+	d.byUser[owner] += size
+	return token, nil
+}
+
+// Get retrieves the file stored under token, if it exists and hasn't
+// expired, refreshing its LRU position.
+func (d *Dropbox) Get(token string) (name string, data []byte, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	el, ok := d.index[token]
+	if !ok {
+		return "", nil, ErrNotFound
+	}
+	e := el.Value.(*entry)
+	if time.Now().After(e.expires) {
+		d.removeLocked(el)
+		return "", nil, ErrNotFound
+	}
+	d.order.MoveToFront(el)
+	return e.name, e.data, nil
+}
+
+// Used returns the total bytes currently stored, across all users.
+func (d *Dropbox) Used() int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.used
+}
+
+// Len returns the number of files currently stored.
+func (d *Dropbox) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.order.Len()
+}
+
+// evictExpiredLocked removes every entry whose TTL has passed. Must be
+// called with mu held.
+func (d *Dropbox) evictExpiredLocked() {
+	now := time.Now()
+	var next *list.Element
+	for el := d.order.Front(); el != nil; el = next {
+		next = el.Next()
+		if now.After(el.Value.(*entry).expires) {
+			d.removeLocked(el)
+		}
+	}
+}
+
+// evictOldestLocked removes the least-recently-used entry, reporting
+// whether there was one to remove. Must be called with mu held.
+func (d *Dropbox) evictOldestLocked() bool {
+	el := d.order.Back()
+	if el == nil {
+		return false
+	}
+	d.removeLocked(el)
+	return true
+}
+
+// removeLocked detaches el and accounts for its removal. Must be called
+// with mu held.
+func (d *Dropbox) removeLocked(el *list.Element) {
+	e := el.Value.(*entry)
+	d.order.Remove(el)
+	delete(d.index, e.token)
+
+	size := int64(len(e.data))
+	d.used -= size
+	d.byUser[e.owner] -= size
+	if d.byUser[e.owner] <= 0 {
+		delete(d.byUser, e.owner)
+	}
+}
+
+// newToken returns a random `/get` token.
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
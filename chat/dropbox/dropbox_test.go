@@ -0,0 +1,118 @@
+package dropbox
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPutGet(t *testing.T) {
+	d := New(1<<20, 1<<20, time.Minute)
+
+	token, err := d.Put("alice", "hello.txt", []byte("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token == "" {
+		t.Fatal("expected a non-empty token")
+	}
+
+	name, data, err := d.Get(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "hello.txt" || string(data) != "hello" {
+		t.Errorf("got (%q, %q)", name, data)
+	}
+}
+
+func TestGetUnknownToken(t *testing.T) {
+	d := New(1<<20, 1<<20, time.Minute)
+	if _, _, err := d.Get("nonexistent"); err != ErrNotFound {
+		t.Errorf("got %v; expected ErrNotFound", err)
+	}
+}
+
+func TestTooLarge(t *testing.T) {
+	d := New(10, 10, time.Minute)
+	if _, err := d.Put("alice", "big.txt", make([]byte, 11)); err != ErrTooLarge {
+		t.Errorf("got %v; expected ErrTooLarge", err)
+	}
+}
+
+func TestUserQuota(t *testing.T) {
+	d := New(1<<20, 10, time.Minute)
+
+	if _, err := d.Put("alice", "a.txt", make([]byte, 6)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Put("alice", "b.txt", make([]byte, 6)); err != ErrQuotaExceeded {
+		t.Errorf("got %v; expected ErrQuotaExceeded", err)
+	}
+
+	// A different user's quota is independent.
+	if _, err := d.Put("bob", "c.txt", make([]byte, 6)); err != nil {
+		t.Errorf("bob's put should have its own quota: %s", err)
+	}
+}
+
+func TestTokenExpires(t *testing.T) {
+	d := New(1<<20, 1<<20, time.Millisecond)
+
+	token, err := d.Put("alice", "f.txt", []byte("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+
+	if _, _, err := d.Get(token); err != ErrNotFound {
+		t.Errorf("got %v; expected expired token to look like ErrNotFound", err)
+	}
+}
+
+func TestEvictsLeastRecentlyUsed(t *testing.T) {
+	d := New(20, 1<<20, time.Minute)
+
+	t1, err := d.Put("alice", "one", make([]byte, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t2, err := d.Put("alice", "two", make([]byte, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Touch t1 so it's the most recently used, leaving t2 as the eviction
+	// candidate when the next put needs room.
+	if _, _, err := d.Get(t1); err != nil {
+		t.Fatal(err)
+	}
+
+	t3, err := d.Put("alice", "three", make([]byte, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := d.Get(t2); err != ErrNotFound {
+		t.Error("expected the least-recently-used entry (two) to have been evicted")
+	}
+	if _, _, err := d.Get(t1); err != nil {
+		t.Error("expected the recently-touched entry (one) to survive")
+	}
+	if _, _, err := d.Get(t3); err != nil {
+		t.Error("expected the newest entry (three) to survive")
+	}
+}
+
+func TestUsedAndLen(t *testing.T) {
+	d := New(1<<20, 1<<20, time.Minute)
+	if d.Used() != 0 || d.Len() != 0 {
+		t.Fatal("expected empty dropbox to start at zero")
+	}
+
+	d.Put("alice", "a", []byte(strings.Repeat("x", 100)))
+	if d.Used() != 100 || d.Len() != 1 {
+		t.Errorf("got (used=%d, len=%d); expected (100, 1)", d.Used(), d.Len())
+	}
+}
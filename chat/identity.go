@@ -0,0 +1,27 @@
+package chat
+
+import "time"
+
+// Identity is a verified binding between a nickname and the fingerprint of
+// an SSH key, established by the `/identify` command: a client proves
+// ownership of the key by having its forwarded agent sign a server-issued
+// challenge, without needing that key loaded in the client's own ssh config.
+type Identity struct {
+	id          Id
+	Fingerprint string
+	VerifiedAt  time.Time
+}
+
+// NewIdentity records that name was verified against fingerprint at t.
+func NewIdentity(name Id, fingerprint string, t time.Time) *Identity {
+	return &Identity{
+		id:          name,
+		Fingerprint: fingerprint,
+		VerifiedAt:  t,
+	}
+}
+
+// Id satisfies Item so Identity can live in a Set, e.g. Host.identities.
+func (i *Identity) Id() Id {
+	return i.id
+}
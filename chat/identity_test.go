@@ -0,0 +1,32 @@
+package chat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIdentitySet(t *testing.T) {
+	s := NewSet()
+	now := time.Now()
+
+	identity := NewIdentity(Id("alice"), "SHA256:abc123", now)
+	if err := s.Add(identity); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.Get(Id("alice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found, ok := got.(*Identity)
+	if !ok {
+		t.Fatalf("got %T; expected *Identity", got)
+	}
+	if found.Fingerprint != "SHA256:abc123" {
+		t.Errorf("got fingerprint %q", found.Fingerprint)
+	}
+	if !found.VerifiedAt.Equal(now) {
+		t.Errorf("got VerifiedAt %v; expected %v", found.VerifiedAt, now)
+	}
+}
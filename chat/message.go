@@ -0,0 +1,116 @@
+package chat
+
+import "time"
+
+// MessageType identifies which Message variant a value is, so backend
+// adapters can serialize/deserialize without type-switching on the concrete
+// Go type across process boundaries.
+type MessageType int
+
+const (
+	MessagePublic MessageType = iota
+	MessageEmote
+	MessageAnnounce
+	MessagePrivate
+	MessageSystem
+)
+
+// String returns the wire spelling of a MessageType.
+func (t MessageType) String() string {
+	switch t {
+	case MessagePublic:
+		return "public"
+	case MessageEmote:
+		return "emote"
+	case MessageAnnounce:
+		return "announce"
+	case MessagePrivate:
+		return "private"
+	case MessageSystem:
+		return "system"
+	}
+	return "unknown"
+}
+
+// Message is the common interface implemented by every message variant
+// broadcast through a Room, and the unit a MessageBackend moves around.
+type Message interface {
+	Type() MessageType
+	From() Id
+	Body() string
+	Sent() time.Time
+}
+
+// baseMessage carries the fields shared by every variant.
+type baseMessage struct {
+	from Id
+	body string
+	sent time.Time
+}
+
+func (m baseMessage) From() Id        { return m.from }
+func (m baseMessage) Body() string    { return m.body }
+func (m baseMessage) Sent() time.Time { return m.sent }
+
+// PublicMessage is an ordinary message sent to the whole room.
+type PublicMessage struct{ baseMessage }
+
+// NewPublicMessage creates a PublicMessage from from, with body sent at t.
+func NewPublicMessage(from Id, body string, t time.Time) PublicMessage {
+	return PublicMessage{baseMessage{from, body, t}}
+}
+
+// Type implements Message.
+func (PublicMessage) Type() MessageType { return MessagePublic }
+
+// EmoteMessage is a `/me ...` action message.
+type EmoteMessage struct{ baseMessage }
+
+// NewEmoteMessage creates an EmoteMessage from from, with body sent at t.
+func NewEmoteMessage(from Id, body string, t time.Time) EmoteMessage {
+	return EmoteMessage{baseMessage{from, body, t}}
+}
+
+// Type implements Message.
+func (EmoteMessage) Type() MessageType { return MessageEmote }
+
+// AnnounceMessage is a server-generated notice, e.g. a join/part line. It
+// has no author.
+type AnnounceMessage struct{ baseMessage }
+
+// NewAnnounceMessage creates an AnnounceMessage with body sent at t.
+func NewAnnounceMessage(body string, t time.Time) AnnounceMessage {
+	return AnnounceMessage{baseMessage{"", body, t}}
+}
+
+// Type implements Message.
+func (AnnounceMessage) Type() MessageType { return MessageAnnounce }
+
+// PrivateMessage is a direct message from one user to another.
+type PrivateMessage struct {
+	baseMessage
+	to Id
+}
+
+// NewPrivateMessage creates a PrivateMessage from from to to, sent at t.
+func NewPrivateMessage(from, to Id, body string, t time.Time) PrivateMessage {
+	return PrivateMessage{baseMessage{from, body, t}, to}
+}
+
+// Type implements Message.
+func (PrivateMessage) Type() MessageType { return MessagePrivate }
+
+// To returns the recipient of the private message.
+func (m PrivateMessage) To() Id { return m.to }
+
+// SystemMessage is a message from the server to a single connection, not
+// broadcast to the room (e.g. a command's reply). It has no author.
+type SystemMessage struct{ baseMessage }
+
+// NewSystemMessage creates a SystemMessage with body sent at t.
+func NewSystemMessage(body string, t time.Time) SystemMessage {
+	return SystemMessage{baseMessage{"", body, t}}
+}
+
+// Type implements Message.
+func (SystemMessage) Type() MessageType { return MessageSystem }
@@ -0,0 +1,50 @@
+package chat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMessageVariants(t *testing.T) {
+	now := time.Now()
+
+	pub := NewPublicMessage(Id("alice"), "hi", now)
+	if pub.Type() != MessagePublic || pub.From() != Id("alice") || pub.Body() != "hi" {
+		t.Errorf("unexpected PublicMessage: %+v", pub)
+	}
+
+	emote := NewEmoteMessage(Id("alice"), "waves", now)
+	if emote.Type() != MessageEmote {
+		t.Errorf("got type %v; expected MessageEmote", emote.Type())
+	}
+
+	announce := NewAnnounceMessage("alice joined", now)
+	if announce.Type() != MessageAnnounce || announce.From() != Id("") {
+		t.Errorf("unexpected AnnounceMessage: %+v", announce)
+	}
+
+	pm := NewPrivateMessage(Id("alice"), Id("bob"), "psst", now)
+	if pm.Type() != MessagePrivate || pm.To() != Id("bob") {
+		t.Errorf("unexpected PrivateMessage: %+v", pm)
+	}
+
+	sys := NewSystemMessage("usage: /foo", now)
+	if sys.Type() != MessageSystem {
+		t.Errorf("got type %v; expected MessageSystem", sys.Type())
+	}
+}
+
+func TestMessageTypeString(t *testing.T) {
+	cases := map[MessageType]string{
+		MessagePublic:   "public",
+		MessageEmote:    "emote",
+		MessageAnnounce: "announce",
+		MessagePrivate:  "private",
+		MessageSystem:   "system",
+	}
+	for typ, want := range cases {
+		if got := typ.String(); got != want {
+			t.Errorf("got %q; expected %q", got, want)
+		}
+	}
+}
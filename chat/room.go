@@ -0,0 +1,219 @@
+package chat
+
+import (
+	"errors"
+	"sync"
+)
+
+// Backend is the message delivery and presence contract Room depends on. It
+// mirrors chat/backend.MessageBackend but is declared here, rather than
+// imported, so that chat/backend (which already imports chat for
+// chat.Message) doesn't become an import cycle; chat/backend's Memory and
+// Redis types satisfy this interface structurally without either package
+// referencing the other.
+type Backend interface {
+	// Publish broadcasts msg to every other node sharing this room.
+	Publish(msg Message) error
+
+	// Subscribe returns a channel of messages published to the room. Redis
+	// drops a node's own Publish calls; Room relies on this in Relay to
+	// avoid delivering its own members' messages to themselves twice.
+	// Memory's Subscribe does deliver its own node's publishes (see Relay).
+	Subscribe() <-chan Message
+
+	// Join and Leave update room presence so Names reflects users
+	// connected to any node.
+	Join(name string) error
+	Leave(name string) error
+
+	// Names lists everyone present in the room, across all nodes.
+	Names() ([]string, error)
+}
+
+// Locator optionally augments a Backend with cross-node presence lookup, so
+// Room can route a PrivateMessage to a recipient connected to some other
+// node before giving up on it. backend.Redis satisfies this structurally
+// via its existing Locate method; Memory's single node has nowhere else to
+// look, so it doesn't need to.
+type Locator interface {
+	// Locate returns the node a connected user's Room currently lives on,
+	// or ok == false if no node has them present.
+	Locate(name string) (node string, ok bool, err error)
+}
+
+// ErrRecipientNotFound is returned by Send for a PrivateMessage whose
+// recipient isn't connected to this room, locally or (per Locator, if the
+// backend supports it) on any other node.
+var ErrRecipientNotFound = errors.New("chat: recipient not found")
+
+// ErrSystemMessageNotRoutable is returned by Send for a SystemMessage:
+// unlike every other Message variant, a SystemMessage carries no recipient,
+// by design (see its doc comment) — it's meant to go straight back to the
+// one connection that triggered it, bypassing Room entirely.
+var ErrSystemMessageNotRoutable = errors.New("chat: system messages are not sent through Room")
+
+// Room is a single chat channel: membership plus message delivery, the
+// latter delegated to a Backend so that multiple ssh-chat processes can
+// share one logical room instead of each being an island.
+type Room struct {
+	members *Set
+	backend Backend
+
+	mu   sync.Mutex
+	subs map[Id]chan Message
+}
+
+// NewRoom creates a Room backed by b. With backend.NewMemory(), there is
+// only ever one node, so Relay is unnecessary; a Redis-backed Room should
+// have Relay started once to pick up messages from other nodes.
+func NewRoom(b Backend) *Room {
+	return &Room{
+		members: NewSet(),
+		backend: b,
+		subs:    map[Id]chan Message{},
+	}
+}
+
+// Relay starts forwarding messages the backend received from other nodes to
+// this Room's locally connected members. It must be started at most once
+// per Room. Single-node backends like Memory have no other nodes to relay
+// from, and Memory's Subscribe also returns this node's own publishes, so
+// Relay on a Memory-backed Room delivers every message to local members
+// twice; such a Room does not call Relay.
+func (r *Room) Relay() {
+	// Subscribe here, synchronously, rather than inside the goroutine
+	// below: otherwise a message the backend publishes right after Relay
+	// returns could be missed, since nothing would be subscribed yet to
+	// receive it.
+	sub := r.backend.Subscribe()
+	go func() {
+		for msg := range sub {
+			r.deliverLocal(msg)
+		}
+	}()
+}
+
+// Join adds item to the room and returns the channel it will receive
+// messages on. The caller is responsible for reading from the channel
+// until calling Leave.
+func (r *Room) Join(item Item) (<-chan Message, error) {
+	if err := r.members.Add(item); err != nil {
+		return nil, err
+	}
+
+	ch := make(chan Message, 16)
+	r.mu.Lock()
+	r.subs[item.Id()] = ch
+	r.mu.Unlock()
+
+	if err := r.backend.Join(string(item.Id())); err != nil {
+		r.mu.Lock()
+		delete(r.subs, item.Id())
+		r.mu.Unlock()
+		r.members.Remove(item)
+		close(ch)
+		return nil, err
+	}
+	return ch, nil
+}
+
+// Leave removes item from the room and closes its delivery channel.
+func (r *Room) Leave(item Item) error {
+	r.mu.Lock()
+	if ch, ok := r.subs[item.Id()]; ok {
+		close(ch)
+		delete(r.subs, item.Id())
+	}
+	r.mu.Unlock()
+
+	r.members.Remove(item)
+	return r.backend.Leave(string(item.Id()))
+}
+
+// Send delivers msg to this room's local members immediately, then
+// publishes it through the backend so other nodes' Relay picks it up for
+// their own members. A PrivateMessage is instead routed to only its
+// recipient, locally or (via Locator) on whichever other node has them, and
+// a SystemMessage is rejected outright; see their doc comments.
+func (r *Room) Send(msg Message) error {
+	switch m := msg.(type) {
+	case PrivateMessage:
+		return r.sendPrivate(m)
+	case SystemMessage:
+		return ErrSystemMessageNotRoutable
+	default:
+		r.deliverLocal(msg)
+		return r.backend.Publish(msg)
+	}
+}
+
+// sendPrivate delivers pm to its recipient alone: directly if they're
+// subscribed on this node, otherwise by publishing through the backend only
+// once Locate (if the backend is a Locator) confirms they're present on
+// some other node. This is what keeps a PM from fanning out to the whole
+// room the way a PublicMessage does.
+func (r *Room) sendPrivate(pm PrivateMessage) error {
+	r.mu.Lock()
+	ch, local := r.subs[pm.to]
+	r.mu.Unlock()
+	if local {
+		select {
+		case ch <- Message(pm):
+		default:
+		}
+		return nil
+	}
+
+	loc, ok := r.backend.(Locator)
+	if !ok {
+		return ErrRecipientNotFound
+	}
+	_, found, err := loc.Locate(string(pm.to))
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrRecipientNotFound
+	}
+	return r.backend.Publish(pm)
+}
+
+// Names lists everyone present in the room, across all nodes sharing this
+// room's backend.
+func (r *Room) Names() ([]string, error) {
+	return r.backend.Names()
+}
+
+// deliverLocal fans a broadcast msg out to every member currently connected
+// to this node, dropping it for any member whose channel is full rather
+// than blocking the sender on a slow reader. It's used for Send's
+// broadcast messages and for whatever Relay picks up from other nodes; a
+// PrivateMessage relayed in from another node is instead routed to only its
+// recipient (mirroring sendPrivate), and a SystemMessage is dropped, since
+// neither is ever meant to reach the whole room.
+func (r *Room) deliverLocal(msg Message) {
+	if pm, ok := msg.(PrivateMessage); ok {
+		r.mu.Lock()
+		ch, ok := r.subs[pm.to]
+		r.mu.Unlock()
+		if ok {
+			select {
+			case ch <- msg:
+			default:
+			}
+		}
+		return
+	}
+	if _, ok := msg.(SystemMessage); ok {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, ch := range r.subs {
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 // The error returned when an added id already exists in the set.
@@ -20,106 +21,370 @@ type Item interface {
 	Id() Id
 }
 
-// Set with string lookup.
-// TODO: Add trie for efficient prefix lookup?
+// numShards is the number of independent trie roots a Set is split across.
+// Sharding by the first byte of an Id means a join/part under one letter
+// doesn't contend with a mention-scan's ListPrefix under another.
+const numShards = 64
+
+// Set with string lookup, backed by a trie per shard rather than a flat map,
+// so Get/In/Remove/ListPrefix are O(k) in the key length instead of O(n) in
+// the set size. Only the choice of shard folds case; within a shard the trie
+// is keyed by each Id exactly as given, so Get is case-sensitive and
+// ListPrefix (which lowercases its prefix) only matches lowercase Ids.
 type Set struct {
-	lookup map[Id]Item
-	sync.RWMutex
+	shards [numShards]*shard
+	count  int64
+}
+
+// shard is one independent trie root with its own lock.
+type shard struct {
+	mu   sync.RWMutex
+	root *trieNode
+}
+
+// trieNode is a single node of a compressed prefix tree (radix trie): the
+// edge leading to it from its parent is labeled with the substring it
+// consumes, so runs of single-child nodes collapse into one edge.
+type trieNode struct {
+	label    string
+	children map[byte]*trieNode
+	item     Item
+	terminal bool
 }
 
 // NewSet creates a new set.
 func NewSet() *Set {
-	return &Set{
-		lookup: map[Id]Item{},
+	s := &Set{}
+	for i := range s.shards {
+		s.shards[i] = &shard{root: &trieNode{}}
 	}
+	return s
+}
+
+// shardFor returns the shard responsible for key, chosen by the case-folded
+// first byte so that keys differing only in case share a shard. The trie
+// within that shard does not fold case.
+func (s *Set) shardFor(key string) *shard {
+	if key == "" {
+		return s.shards[0]
+	}
+	return s.shards[foldByte(key[0])%numShards]
+}
+
+func foldByte(b byte) int {
+	if b >= 'A' && b <= 'Z' {
+		b += 'a' - 'A'
+	}
+	return int(b)
+}
+
+// commonPrefixLen returns the length of the longest common prefix of a and b.
+func commonPrefixLen(a, b string) int {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	i := 0
+	for i < n && a[i] == b[i] {
+		i++
+	}
+	return i
 }
 
 // Clear removes all items and returns the number removed.
 func (s *Set) Clear() int {
-	s.Lock()
-	n := len(s.lookup)
-	s.lookup = map[Id]Item{}
-	s.Unlock()
+	n := int(atomic.SwapInt64(&s.count, 0))
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		sh.root = &trieNode{}
+		sh.mu.Unlock()
+	}
 	return n
 }
 
 // Len returns the size of the set right now.
 func (s *Set) Len() int {
-	return len(s.lookup)
+	return int(atomic.LoadInt64(&s.count))
 }
 
 // In checks if an item exists in this set.
 func (s *Set) In(item Item) bool {
-	s.RLock()
-	_, ok := s.lookup[item.Id()]
-	s.RUnlock()
-	return ok
+	_, err := s.Get(item.Id())
+	return err == nil
 }
 
 // Get returns an item with the given Id.
 func (s *Set) Get(id Id) (Item, error) {
-	s.RLock()
-	item, ok := s.lookup[id]
-	s.RUnlock()
+	sh := s.shardFor(string(id))
+	sh.mu.RLock()
+	item, ok := sh.root.search(string(id))
+	sh.mu.RUnlock()
 
 	if !ok {
 		return nil, ErrItemMissing
 	}
-
 	return item, nil
 }
 
 // Add item to this set if it does not exist already.
 func (s *Set) Add(item Item) error {
-	s.Lock()
-	defer s.Unlock()
+	key := string(item.Id())
+	sh := s.shardFor(key)
 
-	_, found := s.lookup[item.Id()]
-	if found {
-		return ErrIdTaken
-	}
+	sh.mu.Lock()
+	err := sh.root.insert(key, item)
+	sh.mu.Unlock()
 
-	s.lookup[item.Id()] = item
+	if err != nil {
+		return err
+	}
+	atomic.AddInt64(&s.count, 1)
 	return nil
 }
 
 // Remove item from this set.
 func (s *Set) Remove(item Item) error {
-	s.Lock()
-	defer s.Unlock()
-	id := item.Id()
-	_, found := s.lookup[id]
-	if !found {
-		return ErrItemMissing
+	key := string(item.Id())
+	sh := s.shardFor(key)
+
+	sh.mu.Lock()
+	err := sh.root.remove(key)
+	sh.mu.Unlock()
+
+	if err != nil {
+		return err
 	}
-	delete(s.lookup, id)
+	atomic.AddInt64(&s.count, -1)
 	return nil
 }
 
-// Each loops over every item while holding a read lock and applies fn to each
-// element.
+// Each loops over every item. The shard a given item lives in has its read
+// lock held while that item's callback runs, so a mutation to one shard
+// never waits on a slow callback visiting another.
 func (s *Set) Each(fn func(item Item)) {
-	s.RLock()
-	for _, item := range s.lookup {
-		fn(item)
+	for _, sh := range s.shards {
+		sh.mu.RLock()
+		sh.root.walk(func(item Item) bool {
+			fn(item)
+			return true
+		})
+		sh.mu.RUnlock()
 	}
-	s.RUnlock()
 }
 
 // ListPrefix returns a list of items with a prefix, case insensitive.
 func (s *Set) ListPrefix(prefix string) []Item {
-	r := []Item{}
+	return s.ListPrefixN(prefix, 0)
+}
+
+// ListPrefixN is like ListPrefix but stops after collecting n items, for
+// bounding the work done by tab-completion in a busy room. n <= 0 means no
+// limit.
+func (s *Set) ListPrefixN(prefix string, n int) []Item {
 	prefix = strings.ToLower(prefix)
+	r := []Item{}
+
+	if prefix != "" {
+		sh := s.shardFor(prefix)
+		sh.mu.RLock()
+		r = sh.root.listPrefix(prefix, n)
+		sh.mu.RUnlock()
+		return r
+	}
+
+	// Empty prefix matches everything; walk every shard.
+	for _, sh := range s.shards {
+		if n > 0 && len(r) >= n {
+			break
+		}
+		quota := 0
+		if n > 0 {
+			quota = n - len(r)
+		}
+		sh.mu.RLock()
+		items := sh.root.listPrefix("", quota)
+		sh.mu.RUnlock()
+		r = append(r, items...)
+	}
+	if n > 0 && len(r) > n {
+		r = r[:n]
+	}
+	return r
+}
+
+// search walks the trie for an exact key match.
+func (t *trieNode) search(key string) (Item, bool) {
+	n := t
+	for key != "" {
+		child, ok := n.children[key[0]]
+		if !ok || !strings.HasPrefix(key, child.label) {
+			return nil, false
+		}
+		key = key[len(child.label):]
+		n = child
+	}
+	if !n.terminal {
+		return nil, false
+	}
+	return n.item, true
+}
+
+// insert adds item under key, splitting edges as needed to keep the trie
+// compressed.
+func (t *trieNode) insert(key string, item Item) error {
+	n := t
+	for {
+		if key == "" {
+			if n.terminal {
+				return ErrIdTaken
+			}
+			n.terminal = true
+			n.item = item
+			return nil
+		}
 
-	s.RLock()
-	defer s.RUnlock()
+		if n.children == nil {
+			n.children = map[byte]*trieNode{}
+		}
+		c := key[0]
+		child, ok := n.children[c]
+		if !ok {
+			n.children[c] = &trieNode{label: key, terminal: true, item: item}
+			return nil
+		}
 
-	for id, item := range s.lookup {
-		if !strings.HasPrefix(string(id), prefix) {
+		common := commonPrefixLen(key, child.label)
+		if common == len(child.label) {
+			// Key fully consumes this edge; descend.
+			n = child
+			key = key[common:]
 			continue
 		}
-		r = append(r, item)
+
+		// Split child's edge at the common prefix.
+		tail := &trieNode{
+			label:    child.label[common:],
+			children: child.children,
+			terminal: child.terminal,
+			item:     child.item,
+		}
+		child.label = child.label[:common]
+		child.terminal = false
+		child.item = nil
+		child.children = map[byte]*trieNode{tail.label[0]: tail}
+
+		rest := key[common:]
+		if rest == "" {
+			child.terminal = true
+			child.item = item
+		} else {
+			child.children[rest[0]] = &trieNode{label: rest, terminal: true, item: item}
+		}
+		return nil
 	}
+}
 
-	return r
-}
\ No newline at end of file
+// remove clears the terminal item at key and collapses any edges left
+// redundant by its removal.
+func (t *trieNode) remove(key string) error {
+	type step struct {
+		parent *trieNode
+		edge   byte
+		node   *trieNode
+	}
+	var path []step
+
+	cur := t
+	for key != "" {
+		c := key[0]
+		child, ok := cur.children[c]
+		if !ok || !strings.HasPrefix(key, child.label) {
+			return ErrItemMissing
+		}
+		path = append(path, step{parent: cur, edge: c, node: child})
+		key = key[len(child.label):]
+		cur = child
+	}
+
+	if !cur.terminal {
+		return ErrItemMissing
+	}
+	cur.terminal = false
+	cur.item = nil
+
+	// Prune dead leaves and merge single-child nodes back into their
+	// parent edge, from the removed node upward.
+	for i := len(path) - 1; i >= 0; i-- {
+		st := path[i]
+		switch {
+		case st.node.terminal:
+			return nil
+		case len(st.node.children) == 0:
+			delete(st.parent.children, st.edge)
+		case len(st.node.children) == 1:
+			var only *trieNode
+			for _, c := range st.node.children {
+				only = c
+			}
+			merged := &trieNode{
+				label:    st.node.label + only.label,
+				children: only.children,
+				terminal: only.terminal,
+				item:     only.item,
+			}
+			st.parent.children[st.edge] = merged
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// listPrefix finds the node at the end of prefix, if any, and collects up to
+// n items from its subtree (n <= 0 means unlimited).
+func (t *trieNode) listPrefix(prefix string, limit int) []Item {
+	node := t
+	remaining := prefix
+	for remaining != "" {
+		child, ok := node.children[remaining[0]]
+		if !ok {
+			return nil
+		}
+		switch {
+		case len(remaining) <= len(child.label):
+			if !strings.HasPrefix(child.label, remaining) {
+				return nil
+			}
+			node = child
+			remaining = ""
+		case strings.HasPrefix(remaining, child.label):
+			remaining = remaining[len(child.label):]
+			node = child
+		default:
+			return nil
+		}
+	}
+
+	var out []Item
+	node.walk(func(item Item) bool {
+		out = append(out, item)
+		return limit <= 0 || len(out) < limit
+	})
+	return out
+}
+
+// walk visits every terminal node in the subtree rooted at t in no
+// particular order, calling fn for each item until fn returns false.
+func (t *trieNode) walk(fn func(Item) bool) bool {
+	if t.terminal {
+		if !fn(t.item) {
+			return false
+		}
+	}
+	for _, child := range t.children {
+		if !child.walk(fn) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,194 @@
+package chat
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+)
+
+type testItem Id
+
+func (t testItem) Id() Id {
+	return Id(t)
+}
+
+func TestSetAddGetRemove(t *testing.T) {
+	s := NewSet()
+
+	if err := s.Add(testItem("foo")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add(testItem("foo")); err != ErrIdTaken {
+		t.Errorf("got %v; expected ErrIdTaken", err)
+	}
+
+	item, err := s.Get(Id("foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Id() != Id("foo") {
+		t.Errorf("got %q", item.Id())
+	}
+
+	if !s.In(testItem("foo")) {
+		t.Error("expected foo to be in the set")
+	}
+
+	if err := s.Remove(testItem("foo")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Remove(testItem("foo")); err != ErrItemMissing {
+		t.Errorf("got %v; expected ErrItemMissing", err)
+	}
+	if _, err := s.Get(Id("foo")); err != ErrItemMissing {
+		t.Errorf("got %v; expected ErrItemMissing", err)
+	}
+}
+
+func TestSetSharedPrefixes(t *testing.T) {
+	s := NewSet()
+	ids := []string{"foo", "foobar", "foobaz", "fo", "food"}
+	for _, id := range ids {
+		if err := s.Add(testItem(id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, id := range ids {
+		if _, err := s.Get(Id(id)); err != nil {
+			t.Errorf("Get(%q) failed: %s", id, err)
+		}
+	}
+
+	if err := s.Remove(testItem("foo")); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"foobar", "foobaz", "fo", "food"} {
+		if _, err := s.Get(Id(id)); err != nil {
+			t.Errorf("Get(%q) failed after removing foo: %s", id, err)
+		}
+	}
+	if _, err := s.Get(Id("foo")); err != ErrItemMissing {
+		t.Errorf("got %v; expected foo to be gone", err)
+	}
+}
+
+func TestSetListPrefix(t *testing.T) {
+	s := NewSet()
+	for _, id := range []string{"alice", "alex", "bob", "alan"} {
+		s.Add(testItem(id))
+	}
+
+	got := s.ListPrefix("al")
+	if len(got) != 3 {
+		t.Errorf("got %d results; expected 3: %v", len(got), got)
+	}
+
+	got = s.ListPrefix("AL")
+	if len(got) != 3 {
+		t.Errorf("expected case-insensitive match, got %d results", len(got))
+	}
+
+	if got := s.ListPrefix("zzz"); len(got) != 0 {
+		t.Errorf("got %d results; expected 0", len(got))
+	}
+}
+
+func TestSetListPrefixN(t *testing.T) {
+	s := NewSet()
+	for i := 0; i < 50; i++ {
+		s.Add(testItem("user" + strconv.Itoa(i)))
+	}
+
+	got := s.ListPrefixN("user", 10)
+	if len(got) != 10 {
+		t.Errorf("got %d results; expected 10", len(got))
+	}
+
+	all := s.ListPrefixN("user", 0)
+	if len(all) != 50 {
+		t.Errorf("got %d results; expected 50 with no limit", len(all))
+	}
+}
+
+func TestSetClearLen(t *testing.T) {
+	s := NewSet()
+	for i := 0; i < 10; i++ {
+		s.Add(testItem(fmt.Sprintf("item%d", i)))
+	}
+	if s.Len() != 10 {
+		t.Errorf("got Len() = %d; expected 10", s.Len())
+	}
+	if n := s.Clear(); n != 10 {
+		t.Errorf("Clear() returned %d; expected 10", n)
+	}
+	if s.Len() != 0 {
+		t.Errorf("got Len() = %d after Clear; expected 0", s.Len())
+	}
+}
+
+func TestSetEach(t *testing.T) {
+	s := NewSet()
+	want := map[Id]bool{"a": true, "b": true, "c": true}
+	for id := range want {
+		s.Add(testItem(id))
+	}
+
+	got := map[Id]bool{}
+	s.Each(func(item Item) {
+		got[item.Id()] = true
+	})
+
+	if len(got) != len(want) {
+		t.Errorf("got %d items; expected %d", len(got), len(want))
+	}
+	for id := range want {
+		if !got[id] {
+			t.Errorf("missing %q from Each", id)
+		}
+	}
+}
+
+// benchSetSize is the member count used by the benchmarks below, matching
+// the kind of busy-room size ListPrefix needs to stay fast at.
+const benchSetSize = 10000
+
+func buildBenchSet(b *testing.B) *Set {
+	s := NewSet()
+	for i := 0; i < benchSetSize; i++ {
+		s.Add(testItem("user" + strconv.Itoa(i)))
+	}
+	return s
+}
+
+func BenchmarkSetAdd(b *testing.B) {
+	s := NewSet()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Add(testItem("user" + strconv.Itoa(i)))
+	}
+}
+
+func BenchmarkSetGet(b *testing.B) {
+	s := buildBenchSet(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Get(Id("user" + strconv.Itoa(i%benchSetSize)))
+	}
+}
+
+func BenchmarkSetListPrefix(b *testing.B) {
+	s := buildBenchSet(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.ListPrefix("user123")
+	}
+}
+
+func BenchmarkSetListPrefixN(b *testing.B) {
+	s := buildBenchSet(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.ListPrefixN("user", 20)
+	}
+}
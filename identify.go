@@ -0,0 +1,134 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shazow/ssh-chat/chat"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// challengeSize is the number of random bytes sent to a forwarded agent for
+// an `/identify` signature.
+const challengeSize = 32
+
+// NewChallenge returns a fresh random challenge for `/identify` to ask the
+// caller's forwarded agent to sign, proving ownership of a keyed nickname
+// without that key being loaded in the client's own ssh config.
+func NewChallenge() ([]byte, error) {
+	b := make([]byte, challengeSize)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// VerifyChallenge checks that sig is key's signature over challenge, as
+// produced by the forwarded agent in response to `/identify`.
+func VerifyChallenge(key ssh.PublicKey, challenge []byte, sig *ssh.Signature) error {
+	return key.Verify(challenge, sig)
+}
+
+// AgentAllowList restricts which authenticated usernames may request
+// auth-agent-req@openssh.com forwarding, so operators opt individual users
+// into the feature rather than exposing every connection's agent.
+type AgentAllowList struct {
+	names map[string]struct{}
+}
+
+// NewAgentAllowList builds an allow-list from a set of usernames.
+func NewAgentAllowList(names ...string) *AgentAllowList {
+	l := &AgentAllowList{names: map[string]struct{}{}}
+	for _, n := range names {
+		l.names[n] = struct{}{}
+	}
+	return l
+}
+
+// Allowed reports whether name may request agent forwarding.
+func (l *AgentAllowList) Allowed(name string) bool {
+	if l == nil {
+		return false
+	}
+	_, ok := l.names[name]
+	return ok
+}
+
+// identify runs the `/identify` flow for a connection authenticated as
+// name, using its forwarded agent ag to sign a fresh challenge and, on a
+// valid signature, recording the resulting Identity in identities. A valid
+// signature only proves ownership of whatever key the agent offered, not
+// that it's the key name is bound to, so re-running `/identify` refreshes
+// the identity on file if the fingerprint matches, and is rejected if it
+// doesn't -- otherwise anyone could claim someone else's already-verified
+// name just by offering a different key.
+func identify(name string, ag agent.Agent, allowList *AgentAllowList, identities *chat.Set) (string, error) {
+	if !allowList.Allowed(name) {
+		return "", fmt.Errorf("/identify: agent forwarding is not enabled for %s", name)
+	}
+	if ag == nil {
+		return "", errors.New("/identify: no forwarded agent; reconnect with ssh -A")
+	}
+
+	signers, err := ag.Signers()
+	if err != nil {
+		return "", err
+	}
+	if len(signers) == 0 {
+		return "", errors.New("/identify: forwarded agent has no keys loaded")
+	}
+	signer := signers[0]
+
+	challenge, err := NewChallenge()
+	if err != nil {
+		return "", err
+	}
+	sig, err := signer.Sign(rand.Reader, challenge)
+	if err != nil {
+		return "", fmt.Errorf("/identify: agent refused to sign challenge: %s", err)
+	}
+	if err := VerifyChallenge(signer.PublicKey(), challenge, sig); err != nil {
+		return "", fmt.Errorf("/identify: signature did not verify: %s", err)
+	}
+
+	fingerprint := ssh.FingerprintSHA256(signer.PublicKey())
+	if old, err := identities.Get(chat.Id(name)); err == nil {
+		if existing := old.(*chat.Identity); existing.Fingerprint != fingerprint {
+			return "", fmt.Errorf("/identify: %s is already identified with a different key", name)
+		}
+		identities.Remove(old)
+	}
+	if err := identities.Add(chat.NewIdentity(chat.Id(name), fingerprint, time.Now())); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("identified as %s (%s)", name, fingerprint), nil
+}
+
+// NewIdentifyCommands builds the `/identify` and `/whois` commands for one
+// connection: name is who's connected, ag is their forwarded agent (nil if
+// they didn't request forwarding), and identities is the shared store
+// `/whois` reads from. Each connection gets its own CommandSet from this
+// since, unlike `/ban`, the handler needs connection-specific state (the
+// caller's own name and forwarded agent) that chat.CommandSet's
+// args-only Handler doesn't carry.
+func NewIdentifyCommands(name string, ag agent.Agent, allowList *AgentAllowList, identities *chat.Set) *chat.CommandSet {
+	cs := chat.NewCommandSet()
+	cs.Add("identify", func(args []string) (string, error) {
+		return identify(name, ag, allowList, identities)
+	})
+	cs.Add("whois", func(args []string) (string, error) {
+		target := name
+		if len(args) > 0 {
+			target = args[0]
+		}
+		item, err := identities.Get(chat.Id(target))
+		if err != nil {
+			return chat.FormatWhois(nil), nil
+		}
+		return chat.FormatWhois(item.(*chat.Identity)), nil
+	})
+	return cs
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+
+	"github.com/shazow/ssh-chat/chat"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+func TestNewChallengeSize(t *testing.T) {
+	a, err := NewChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a) != challengeSize {
+		t.Errorf("got challenge length %d; expected %d", len(a), challengeSize)
+	}
+	if string(a) == string(b) {
+		t.Error("expected two challenges to differ")
+	}
+}
+
+func TestVerifyChallenge(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	challenge, err := NewChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sig, err := signer.Sign(rand.Reader, challenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := VerifyChallenge(signer.PublicKey(), challenge, sig); err != nil {
+		t.Errorf("expected valid signature to verify: %s", err)
+	}
+
+	other, err := NewChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyChallenge(signer.PublicKey(), other, sig); err == nil {
+		t.Error("expected signature over a different challenge to fail verification")
+	}
+}
+
+func TestAgentAllowList(t *testing.T) {
+	l := NewAgentAllowList("alice", "bob")
+	if !l.Allowed("alice") {
+		t.Error("expected alice to be allowed")
+	}
+	if l.Allowed("mallory") {
+		t.Error("expected mallory to be denied")
+	}
+
+	var nilList *AgentAllowList
+	if nilList.Allowed("alice") {
+		t.Error("expected nil allow-list to deny everyone")
+	}
+}
+
+func TestIdentifyCommands(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ag := agent.NewKeyring()
+	if err := ag.Add(agent.AddedKey{PrivateKey: key}); err != nil {
+		t.Fatal(err)
+	}
+
+	allowList := NewAgentAllowList("alice")
+	identities := chat.NewSet()
+
+	cs := NewIdentifyCommands("alice", ag, allowList, identities)
+	reply, err := cs.Run("/identify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(reply, "identified as alice") {
+		t.Errorf("got %q", reply)
+	}
+
+	whois, err := cs.Run("/whois")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(whois, "identity: verified ") {
+		t.Errorf("got %q; expected a verified identity", whois)
+	}
+
+	if item, err := identities.Get(chat.Id("alice")); err != nil {
+		t.Errorf("expected alice to be recorded in identities: %s", err)
+	} else if item.(*chat.Identity).Fingerprint == "" {
+		t.Error("expected a non-empty fingerprint")
+	}
+}
+
+func TestIdentifyRejectsDifferentKey(t *testing.T) {
+	key1, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key2, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	allowList := NewAgentAllowList("alice")
+	identities := chat.NewSet()
+
+	ag1 := agent.NewKeyring()
+	if err := ag1.Add(agent.AddedKey{PrivateKey: key1}); err != nil {
+		t.Fatal(err)
+	}
+	cs := NewIdentifyCommands("alice", ag1, allowList, identities)
+	if _, err := cs.Run("/identify"); err != nil {
+		t.Fatal(err)
+	}
+	item, err := identities.Get(chat.Id("alice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := item.(*chat.Identity).Fingerprint
+
+	// A second connection presenting a different key must not be able to
+	// silently take over alice's already-verified identity.
+	ag2 := agent.NewKeyring()
+	if err := ag2.Add(agent.AddedKey{PrivateKey: key2}); err != nil {
+		t.Fatal(err)
+	}
+	cs = NewIdentifyCommands("alice", ag2, allowList, identities)
+	if _, err := cs.Run("/identify"); err == nil {
+		t.Error("expected /identify with a different key to be rejected")
+	}
+
+	item, err = identities.Get(chat.Id("alice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.(*chat.Identity).Fingerprint != first {
+		t.Error("expected alice's original identity to survive the rejected re-identify")
+	}
+
+	// The original key can still re-identify, e.g. on reconnect.
+	cs = NewIdentifyCommands("alice", ag1, allowList, identities)
+	if _, err := cs.Run("/identify"); err != nil {
+		t.Errorf("expected re-identify with the same key to succeed: %s", err)
+	}
+}
+
+func TestIdentifyCommandsDenied(t *testing.T) {
+	allowList := NewAgentAllowList("alice")
+	identities := chat.NewSet()
+
+	// Not on the allow-list.
+	cs := NewIdentifyCommands("mallory", agent.NewKeyring(), allowList, identities)
+	if _, err := cs.Run("/identify"); err == nil {
+		t.Error("expected /identify to be denied for a non-allow-listed user")
+	}
+
+	// Allow-listed but didn't forward an agent.
+	cs = NewIdentifyCommands("alice", nil, allowList, identities)
+	if _, err := cs.Run("/identify"); err == nil {
+		t.Error("expected /identify to fail without a forwarded agent")
+	}
+
+	// /whois for someone never identified.
+	reply, err := cs.Run("/whois bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply != "identity: not verified" {
+		t.Errorf("got %q", reply)
+	}
+}